@@ -0,0 +1,353 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultAlertsPath is where alert rules are persisted, alongside the other
+// flat-file state (.watchlists.json).
+const defaultAlertsPath = ".alerts.json"
+
+// defaultAlertCooldown is how long an alert stays disarmed after firing,
+// before it re-arms and can fire again.
+const defaultAlertCooldown = time.Hour
+
+// AlertKind is what an alert rule watches.
+type AlertKind string
+
+const (
+	AlertPrice         AlertKind = "price"
+	AlertPercentChange AlertKind = "percent_change"
+	AlertVolumeSpike   AlertKind = "volume_spike"
+)
+
+// Direction is which side of the threshold triggers the alert.
+type Direction string
+
+const (
+	DirectionAbove Direction = ">"
+	DirectionBelow Direction = "<"
+)
+
+// Window is the lookback window for a percent-change alert, matching the
+// PercentChange1h/24h/7d fields on Quote.
+type Window string
+
+const (
+	Window1h  Window = "1h"
+	Window24h Window = "24h"
+	Window7d  Window = "7d"
+)
+
+// Alert is a single user-defined rule: watch symbol's kind against
+// threshold, and notify ChatID when it crosses.
+type Alert struct {
+	ID        int64         `json:"id"`
+	ChatID    int64         `json:"chat_id"`
+	Symbol    string        `json:"symbol"`
+	Kind      AlertKind     `json:"kind"`
+	Direction Direction     `json:"direction"`
+	Window    Window        `json:"window,omitempty"`
+	Threshold float64       `json:"threshold"`
+	Cooldown  time.Duration `json:"cooldown"`
+	Armed     bool          `json:"armed"`
+	LastFired time.Time     `json:"last_fired"`
+}
+
+// Evaluate reports whether the alert should fire for the given quote, along
+// with the message to send. It does not mutate the alert or check Armed -
+// callers are expected to do that via AlertStore so state stays persisted.
+func (a *Alert) Evaluate(q Quote) (bool, string) {
+	var value float64
+	switch a.Kind {
+	case AlertPrice:
+		value = q.Price
+	case AlertPercentChange:
+		switch a.Window {
+		case Window1h:
+			value = q.PercentChange1h
+		case Window24h:
+			value = q.PercentChange24h
+		case Window7d:
+			value = q.PercentChange7d
+		default:
+			return false, ""
+		}
+	case AlertVolumeSpike:
+		if q.Source == "coingecko" {
+			log.WithField("symbol", a.Symbol).Warn("volume-spike alerts aren't supported by the coingecko provider, skipping")
+			return false, ""
+		}
+		value = q.VolumeChange24h
+	default:
+		return false, ""
+	}
+
+	if !crossesThreshold(a.Direction, value, a.Threshold) {
+		return false, ""
+	}
+	return true, a.describeTrigger(value)
+}
+
+func (a *Alert) describeTrigger(value float64) string {
+	switch a.Kind {
+	case AlertPrice:
+		return fmt.Sprintf("🔔 %s price is %.2f (%s %.2f)", a.Symbol, value, a.Direction, a.Threshold)
+	case AlertPercentChange:
+		return fmt.Sprintf("🔔 %s %s change is %.2f%% (%s %.2f%%)", a.Symbol, a.Window, value, a.Direction, a.Threshold)
+	case AlertVolumeSpike:
+		return fmt.Sprintf("🔔 %s 24h volume change is %.2f%% (%s %.2f%%)", a.Symbol, value, a.Direction, a.Threshold)
+	default:
+		return fmt.Sprintf("🔔 %s alert triggered", a.Symbol)
+	}
+}
+
+func crossesThreshold(dir Direction, value, threshold float64) bool {
+	switch dir {
+	case DirectionAbove:
+		return value > threshold
+	case DirectionBelow:
+		return value < threshold
+	default:
+		return false
+	}
+}
+
+// String renders the alert the way /alerts lists it back to the user.
+func (a *Alert) String() string {
+	state := "armed"
+	if !a.Armed {
+		state = fmt.Sprintf("cooling down until %s", a.LastFired.Add(a.Cooldown).Format("15:04:05"))
+	}
+	switch a.Kind {
+	case AlertPercentChange:
+		return fmt.Sprintf("#%d %s %%%s %s %.2f (%s)", a.ID, a.Symbol, a.Window, a.Direction, a.Threshold, state)
+	case AlertVolumeSpike:
+		return fmt.Sprintf("#%d %s %%vol %s %.2f (%s)", a.ID, a.Symbol, a.Direction, a.Threshold, state)
+	default:
+		return fmt.Sprintf("#%d %s %s %.2f (%s)", a.ID, a.Symbol, a.Direction, a.Threshold, state)
+	}
+}
+
+// AlertStore persists alert rules to a flat JSON file, the same way
+// WatchlistStore does for watchlists.
+type AlertStore struct {
+	mu     sync.Mutex
+	path   string
+	nextID int64
+	alerts map[int64]*Alert
+}
+
+func NewAlertStore(path string) (*AlertStore, error) {
+	s := &AlertStore{path: path, alerts: make(map[int64]*Alert)}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *AlertStore) load() error {
+	raw, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var alerts []*Alert
+	if err := json.Unmarshal(raw, &alerts); err != nil {
+		return err
+	}
+	for _, a := range alerts {
+		s.alerts[a.ID] = a
+		if a.ID >= s.nextID {
+			s.nextID = a.ID + 1
+		}
+	}
+	return nil
+}
+
+// save must be called with s.mu held.
+func (s *AlertStore) save() error {
+	alerts := make([]*Alert, 0, len(s.alerts))
+	for _, a := range s.alerts {
+		alerts = append(alerts, a)
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].ID < alerts[j].ID })
+
+	raw, err := json.MarshalIndent(alerts, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, raw, 0600)
+}
+
+// Add assigns an id to a new alert, persists it, and returns the id.
+func (s *AlertStore) Add(a *Alert) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a.ID = s.nextID
+	s.nextID++
+	a.Armed = true
+	if a.Cooldown == 0 {
+		a.Cooldown = defaultAlertCooldown
+	}
+	s.alerts[a.ID] = a
+	return a.ID, s.save()
+}
+
+// Remove deletes alert id, but only if it belongs to chatID.
+func (s *AlertStore) Remove(chatID, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.alerts[id]
+	if !ok || a.ChatID != chatID {
+		return fmt.Errorf("no alert #%d for this chat", id)
+	}
+	delete(s.alerts, id)
+	return s.save()
+}
+
+// ListForChat returns chatID's alerts, sorted by id.
+func (s *AlertStore) ListForChat(chatID int64) []*Alert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alerts := make([]*Alert, 0)
+	for _, a := range s.alerts {
+		if a.ChatID == chatID {
+			alerts = append(alerts, a)
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].ID < alerts[j].ID })
+	return alerts
+}
+
+// Symbols returns the distinct symbols that have at least one alert set, so
+// the poller can fetch quotes for a symbol even if no chat watches it.
+func (s *AlertStore) Symbols() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, a := range s.alerts {
+		seen[a.Symbol] = true
+	}
+	symbols := make([]string, 0, len(seen))
+	for sym := range seen {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Due re-arms any alert whose cooldown has elapsed and returns every
+// currently-armed alert, ready to be evaluated against fresh quotes.
+func (s *AlertStore) Due(now time.Time) []*Alert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	due := make([]*Alert, 0)
+	dirty := false
+	for _, a := range s.alerts {
+		if !a.Armed && now.Sub(a.LastFired) >= a.Cooldown {
+			a.Armed = true
+			dirty = true
+		}
+		if a.Armed {
+			due = append(due, a)
+		}
+	}
+	if dirty {
+		if err := s.save(); err != nil {
+			log.WithError(err).Warn("could not persist re-armed alerts")
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+	return due
+}
+
+// MarkFired disarms id and records when it fired, starting its cooldown.
+func (s *AlertStore) MarkFired(id int64, firedAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a, ok := s.alerts[id]
+	if !ok {
+		return fmt.Errorf("no alert #%d", id)
+	}
+	a.Armed = false
+	a.LastFired = firedAt
+	return s.save()
+}
+
+// parseAlertRule parses the condition portion of /alert, e.g.
+// ["SOL", ">", "200"] or ["ETH", "%24h", "<", "-5"] or ["SOL", "%vol", ">", "50"].
+func parseAlertRule(chatID int64, args []string) (*Alert, error) {
+	if len(args) < 3 {
+		return nil, fmt.Errorf("usage: /alert SYMBOL > THRESHOLD | /alert SYMBOL %%24h < THRESHOLD | /alert SYMBOL %%vol > THRESHOLD")
+	}
+
+	symbol := strings.ToUpper(args[0])
+	token := args[1]
+
+	var kind AlertKind
+	var window Window
+	var dirStr, thresholdStr string
+
+	switch {
+	case token == string(DirectionAbove) || token == string(DirectionBelow):
+		kind = AlertPrice
+		dirStr = token
+		thresholdStr = args[2]
+
+	case strings.HasPrefix(token, "%"):
+		if len(args) < 4 {
+			return nil, fmt.Errorf("usage: /alert SYMBOL %%24h < THRESHOLD")
+		}
+		suffix := strings.TrimPrefix(token, "%")
+		if suffix == "vol" {
+			kind = AlertVolumeSpike
+		} else {
+			kind = AlertPercentChange
+			window = Window(suffix)
+			if window != Window1h && window != Window24h && window != Window7d {
+				return nil, fmt.Errorf("unknown window %q, use 1h, 24h or 7d", suffix)
+			}
+		}
+		dirStr = args[2]
+		thresholdStr = args[3]
+
+	default:
+		return nil, fmt.Errorf("unrecognized condition %q", token)
+	}
+
+	if dirStr != string(DirectionAbove) && dirStr != string(DirectionBelow) {
+		return nil, fmt.Errorf("direction must be > or <, got %q", dirStr)
+	}
+	threshold, err := strconv.ParseFloat(thresholdStr, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid threshold %q", thresholdStr)
+	}
+
+	return &Alert{
+		ChatID:    chatID,
+		Symbol:    symbol,
+		Kind:      kind,
+		Direction: Direction(dirStr),
+		Window:    window,
+		Threshold: threshold,
+	}, nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"image/color"
+	"math"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// CandlestickPlotter draws a set of Candles as classic OHLC candlesticks:
+// a wick spanning high/low and a filled body spanning open/close. gonum/plot
+// doesn't ship a candlestick plotter, so this implements plot.Plotter and
+// plot.DataRanger directly.
+type CandlestickPlotter struct {
+	Candles   []Candle
+	ColorUp   color.Color
+	ColorDown color.Color
+	LineWidth vg.Length
+	BodyWidth vg.Length
+}
+
+// NewCandlestickPlotter builds a plotter with sensible default colors and
+// widths for candles.
+func NewCandlestickPlotter(candles []Candle) *CandlestickPlotter {
+	return &CandlestickPlotter{
+		Candles:   candles,
+		ColorUp:   color.RGBA{G: 180, A: 255},
+		ColorDown: color.RGBA{R: 200, A: 255},
+		LineWidth: vg.Points(1),
+		BodyWidth: vg.Points(4),
+	}
+}
+
+// Plot implements plot.Plotter.
+func (c *CandlestickPlotter) Plot(canv draw.Canvas, plt *plot.Plot) {
+	trX, trY := plt.Transforms(&canv)
+
+	for i, candle := range c.Candles {
+		x := trX(float64(i))
+		yHigh := trY(candle.High)
+		yLow := trY(candle.Low)
+		yOpen := trY(candle.Open)
+		yClose := trY(candle.Close)
+
+		col := c.ColorUp
+		if candle.Close < candle.Open {
+			col = c.ColorDown
+		}
+
+		canv.StrokeLine2(draw.LineStyle{Color: col, Width: c.LineWidth}, x, yLow, x, yHigh)
+
+		top, bottom := yOpen, yClose
+		if top < bottom {
+			top, bottom = bottom, top
+		}
+		body := []vg.Point{
+			{X: x - c.BodyWidth/2, Y: bottom},
+			{X: x + c.BodyWidth/2, Y: bottom},
+			{X: x + c.BodyWidth/2, Y: top},
+			{X: x - c.BodyWidth/2, Y: top},
+		}
+		canv.FillPolygon(col, body)
+	}
+}
+
+// DataRange implements plot.DataRanger.
+func (c *CandlestickPlotter) DataRange() (xmin, xmax, ymin, ymax float64) {
+	ymin, ymax = math.Inf(1), math.Inf(-1)
+	for _, candle := range c.Candles {
+		ymin = math.Min(ymin, candle.Low)
+		ymax = math.Max(ymax, candle.High)
+	}
+	xmin = 0
+	xmax = float64(len(c.Candles) - 1)
+	return xmin, xmax, ymin, ymax
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"os"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+	"gonum.org/v1/plot/vg/vgimg"
+)
+
+// renderChart renders up to limit candles for symbol/timeframe from the
+// store, with the given overlays drawn on top (RSI gets its own stacked
+// subplot below the price chart), and saves the result to disk. It errors
+// out (rather than panicking) when there's no history yet, or when called
+// for a symbol the store has never seen.
+func renderChart(priceStore *PriceStore, overlays []Overlay, symbol string, tf Timeframe, limit int, fileSuffix string) (string, error) {
+	candles, err := priceStore.Candles(symbol, tf, limit)
+	if err != nil {
+		return "", fmt.Errorf("could not load candles for %s: %w", symbol, err)
+	}
+	if len(candles) == 0 {
+		return "", fmt.Errorf("no chart history yet for %s", symbol)
+	}
+	closes := closesOf(candles)
+
+	hasOverlay := func(o Overlay) bool {
+		for _, have := range overlays {
+			if have == o {
+				return true
+			}
+		}
+		return false
+	}
+
+	pricePlot := plot.New()
+	pricePlot.Title.Text = fmt.Sprintf("%s (%s)", symbol, tf)
+	pricePlot.X.Label.Text = "Time"
+	pricePlot.Y.Label.Text = "Price, USD"
+	pricePlot.Add(NewCandlestickPlotter(candles))
+
+	if hasOverlay(OverlayBollinger) {
+		if mid, upper, lower := bollingerBands(closes, bollingerWindow, bollingerK); mid != nil {
+			addPriceLine(pricePlot, upper, color.RGBA{R: 150, G: 150, B: 150, A: 255})
+			addPriceLine(pricePlot, lower, color.RGBA{R: 150, G: 150, B: 150, A: 255})
+			addPriceLine(pricePlot, mid, color.RGBA{R: 150, G: 150, B: 150, A: 255})
+		}
+	}
+	if hasOverlay(OverlaySMA) {
+		if pts := smaSeries(closes, smaWindow); pts != nil {
+			addPriceLine(pricePlot, pts, color.RGBA{B: 220, A: 255})
+		}
+	}
+	if hasOverlay(OverlayEMA) {
+		if pts := emaSeries(closes, emaWindow); pts != nil {
+			addPriceLine(pricePlot, pts, color.RGBA{R: 220, G: 140, A: 255})
+		}
+	}
+
+	plotName := fmt.Sprintf(".chart.%s.%s%s.png", symbol, tf, fileSuffix)
+
+	if !hasOverlay(OverlayRSI) {
+		if err := pricePlot.Save(6*vg.Inch, 6*vg.Inch, plotName); err != nil {
+			return "", err
+		}
+		return plotName, nil
+	}
+
+	rsiPlot := plot.New()
+	rsiPlot.Y.Label.Text = "RSI(14)"
+	rsiPlot.Y.Min = 0
+	rsiPlot.Y.Max = 100
+	if pts := rsiSeries(closes); pts != nil {
+		rsiLine, err := plotter.NewLine(pts)
+		if err == nil {
+			rsiLine.Color = color.RGBA{R: 150, A: 255}
+			rsiPlot.Add(rsiLine)
+		}
+	}
+
+	if err := saveStacked(pricePlot, rsiPlot, 6*vg.Inch, 7*vg.Inch, plotName); err != nil {
+		return "", err
+	}
+	return plotName, nil
+}
+
+func addPriceLine(p *plot.Plot, pts plotter.XYs, col color.Color) {
+	line, err := plotter.NewLine(pts)
+	if err != nil {
+		return
+	}
+	line.Color = col
+	p.Add(line)
+}
+
+// saveStacked draws the price plot over the top ~75% of the canvas and the
+// RSI plot in the remaining band below it, then writes the combined image
+// to path.
+func saveStacked(pricePlot, rsiPlot *plot.Plot, width, height vg.Length, path string) error {
+	img := vgimg.New(width, height)
+	dc := draw.New(img)
+
+	rsiHeight := height / 4
+	top := draw.Crop(dc, 0, 0, rsiHeight, 0)
+	bottom := draw.Crop(dc, 0, 0, 0, -(height - rsiHeight))
+
+	pricePlot.Draw(top)
+	rsiPlot.Draw(bottom)
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	png := vgimg.PngCanvas{Canvas: img}
+	_, err = png.WriteTo(f)
+	return err
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultChartCfgPath is where each chat's selected overlays are persisted,
+// alongside the other flat-file state (.watchlists.json, .alerts.json).
+const defaultChartCfgPath = ".chartcfg.json"
+
+// ChartConfigStore tracks which indicator overlays each chat wants drawn on
+// its charts.
+type ChartConfigStore struct {
+	mu   sync.Mutex
+	path string
+	data map[int64]map[Overlay]bool
+}
+
+func NewChartConfigStore(path string) (*ChartConfigStore, error) {
+	s := &ChartConfigStore{path: path, data: make(map[int64]map[Overlay]bool)}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *ChartConfigStore) load() error {
+	raw, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var onDisk map[string][]string
+	if err := json.Unmarshal(raw, &onDisk); err != nil {
+		return err
+	}
+	for chatIDStr, overlays := range onDisk {
+		chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		set := make(map[Overlay]bool, len(overlays))
+		for _, o := range overlays {
+			set[Overlay(o)] = true
+		}
+		s.data[chatID] = set
+	}
+	return nil
+}
+
+// save must be called with s.mu held.
+func (s *ChartConfigStore) save() error {
+	onDisk := make(map[string][]string, len(s.data))
+	for chatID, set := range s.data {
+		overlays := make([]string, 0, len(set))
+		for o := range set {
+			overlays = append(overlays, string(o))
+		}
+		sort.Strings(overlays)
+		onDisk[strconv.FormatInt(chatID, 10)] = overlays
+	}
+
+	raw, err := json.MarshalIndent(onDisk, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, raw, 0600)
+}
+
+// Add enables overlay for chatID.
+func (s *ChartConfigStore) Add(chatID int64, overlay Overlay) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data[chatID] == nil {
+		s.data[chatID] = make(map[Overlay]bool)
+	}
+	s.data[chatID][overlay] = true
+	return s.save()
+}
+
+// Remove disables overlay for chatID.
+func (s *ChartConfigStore) Remove(chatID int64, overlay Overlay) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.data[chatID], overlay)
+	return s.save()
+}
+
+// Overlays returns the overlays enabled for chatID, sorted.
+func (s *ChartConfigStore) Overlays(chatID int64) []Overlay {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	overlays := make([]Overlay, 0, len(s.data[chatID]))
+	for o := range s.data[chatID] {
+		overlays = append(overlays, o)
+	}
+	sort.Slice(overlays, func(i, j int) bool { return overlays[i] < overlays[j] })
+	return overlays
+}
+
+func formatOverlayList(overlays []Overlay) string {
+	names := make([]string, len(overlays))
+	for i, o := range overlays {
+		names[i] = string(o)
+	}
+	return strings.Join(names, ", ")
+}
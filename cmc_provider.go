@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const cmcBaseURL = "https://pro-api.coinmarketcap.com"
+
+// cmcRateLimitErrorCode is CMC's error_code for "you've exceeded your API
+// key's request rate limit", returned with HTTP 200 rather than a 429.
+const cmcRateLimitErrorCode = 1008
+
+// cmcStatus mirrors the "status" block CoinMarketCap includes on every
+// response.
+type cmcStatus struct {
+	Timestamp    string  `json:"timestamp"`
+	ErrorCode    int     `json:"error_code"`
+	ErrorMessage *string `json:"error_message"`
+	Elapsed      int     `json:"elapsed"`
+	CreditCount  int     `json:"credit_count"`
+	Notice       *string `json:"notice"`
+}
+
+type cmcQuote struct {
+	Price            float64 `json:"price"`
+	Volume24h        float64 `json:"volume_24h"`
+	VolumeChange24h  float64 `json:"volume_change_24h"`
+	PercentChange1h  float64 `json:"percent_change_1h"`
+	PercentChange24h float64 `json:"percent_change_24h"`
+	PercentChange7d  float64 `json:"percent_change_7d"`
+	MarketCap        float64 `json:"market_cap"`
+	LastUpdated      string  `json:"last_updated"`
+}
+
+type cmcCryptoData struct {
+	ID     int                 `json:"id"`
+	Name   string              `json:"name"`
+	Symbol string              `json:"symbol"`
+	Quote  map[string]cmcQuote `json:"quote"`
+}
+
+type cmcResponse struct {
+	Status cmcStatus                `json:"status"`
+	Data   map[string]cmcCryptoData `json:"data"`
+}
+
+// CMCProvider talks to CoinMarketCap's pro API. It requires CMC_TOKEN to be
+// set and is subject to CMC's per-plan credit limits.
+type CMCProvider struct {
+	client  *http.Client
+	apiKey  string
+	baseURL string
+}
+
+func NewCMCProvider(client *http.Client) *CMCProvider {
+	return &CMCProvider{
+		client:  client,
+		apiKey:  os.Getenv("CMC_TOKEN"),
+		baseURL: cmcBaseURL,
+	}
+}
+
+func (p *CMCProvider) Name() string {
+	return "cmc"
+}
+
+func (p *CMCProvider) Quotes(symbols []string, convert string) (map[string]Quote, error) {
+	if len(symbols) == 0 {
+		return map[string]Quote{}, nil
+	}
+
+	req, err := http.NewRequest("GET", p.baseURL+"/v1/cryptocurrency/quotes/latest", nil)
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	q.Add("symbol", strings.ToUpper(strings.Join(symbols, ",")))
+	q.Add("convert", convert)
+	req.URL.RawQuery = q.Encode()
+	req.Header.Set("Accepts", "application/json")
+	req.Header.Add("X-CMC_PRO_API_KEY", p.apiKey)
+
+	var quotes map[string]Quote
+	err = withRetry(func(attempt int) (time.Duration, error) {
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return 0, fmt.Errorf("cmc: request failed: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return parseRetryAfter(resp.Header.Get("Retry-After")), fmt.Errorf("cmc: rate limited (HTTP 429)")
+		}
+
+		var parsed cmcResponse
+		if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+			return 0, fmt.Errorf("cmc: could not decode response body: %w", err)
+		}
+
+		if parsed.Status.ErrorCode != 0 {
+			msg := "unknown error"
+			if parsed.Status.ErrorMessage != nil {
+				msg = *parsed.Status.ErrorMessage
+			}
+			if parsed.Status.ErrorCode == cmcRateLimitErrorCode {
+				return parseRetryAfter(resp.Header.Get("Retry-After")), fmt.Errorf("cmc: rate limited: %s", msg)
+			}
+			return 0, fmt.Errorf("cmc: error %d: %s", parsed.Status.ErrorCode, msg)
+		}
+
+		quotes = quotesFromCMCResponse(parsed, convert)
+		return 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return quotes, nil
+}
+
+func quotesFromCMCResponse(parsed cmcResponse, convert string) map[string]Quote {
+	keys := make([]string, 0, len(parsed.Data))
+	for key := range parsed.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	quotes := make(map[string]Quote, len(keys))
+	for _, key := range keys {
+		data := parsed.Data[key]
+		cq, ok := data.Quote[convert]
+		if !ok {
+			continue
+		}
+		symbol := strings.ToUpper(data.Symbol)
+		quotes[symbol] = Quote{
+			ID:               key,
+			Symbol:           symbol,
+			Name:             data.Name,
+			Price:            cq.Price,
+			Volume24h:        cq.Volume24h,
+			VolumeChange24h:  cq.VolumeChange24h,
+			PercentChange1h:  cq.PercentChange1h,
+			PercentChange24h: cq.PercentChange24h,
+			PercentChange7d:  cq.PercentChange7d,
+			MarketCap:        cq.MarketCap,
+			LastUpdated:      cq.LastUpdated,
+		}
+	}
+	return quotes
+}
+
+// Ping hits CMC's key/info endpoint, which is cheap (doesn't consume a
+// credit against the quotes limit) and reports whether the key still has
+// room left this month.
+func (p *CMCProvider) Ping() error {
+	req, err := http.NewRequest("GET", p.baseURL+"/v1/key/info", nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accepts", "application/json")
+	req.Header.Add("X-CMC_PRO_API_KEY", p.apiKey)
+
+	return withRetry(func(attempt int) (time.Duration, error) {
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return 0, fmt.Errorf("cmc: ping failed: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return parseRetryAfter(resp.Header.Get("Retry-After")), fmt.Errorf("cmc: ping rate limited")
+		}
+		if resp.StatusCode != http.StatusOK {
+			return 0, fmt.Errorf("cmc: ping returned status %d", resp.StatusCode)
+		}
+		return 0, nil
+	})
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const coingeckoBaseURL = "https://api.coingecko.com/api/v3"
+
+// coingeckoIDCacheTTL bounds how long the symbol->coin-id cache is trusted
+// before it's refetched. CoinGecko's coin list changes rarely, so there's no
+// need to hit /coins/list on every poll.
+const coingeckoIDCacheTTL = time.Hour
+
+// coingeckoListing is the subset of /coins/list we need to resolve a ticker
+// symbol (e.g. "BTC") to the coin id /coins/markets expects (e.g. "bitcoin").
+type coingeckoListing struct {
+	ID     string `json:"id"`
+	Symbol string `json:"symbol"`
+	Name   string `json:"name"`
+}
+
+// coingeckoMarket mirrors the subset of fields we use from the
+// /coins/markets response.
+type coingeckoMarket struct {
+	ID                           string  `json:"id"`
+	Symbol                       string  `json:"symbol"`
+	Name                         string  `json:"name"`
+	CurrentPrice                 float64 `json:"current_price"`
+	MarketCap                    float64 `json:"market_cap"`
+	TotalVolume                  float64 `json:"total_volume"`
+	PriceChangePercentage1hInCur float64 `json:"price_change_percentage_1h_in_currency"`
+	PriceChangePercentage24hCur  float64 `json:"price_change_percentage_24h_in_currency"`
+	PriceChangePercentage7dInCur float64 `json:"price_change_percentage_7d_in_currency"`
+	LastUpdated                  string  `json:"last_updated"`
+}
+
+// CoinGeckoProvider talks to CoinGecko's public markets API. It requires no
+// API key, making it a usable fallback (or primary) when a paid CMC key
+// isn't available.
+type CoinGeckoProvider struct {
+	client  *http.Client
+	baseURL string
+
+	idCacheMu  sync.Mutex
+	idCache    map[string]string // uppercase symbol -> coin id
+	idCachedAt time.Time
+}
+
+func NewCoinGeckoProvider(client *http.Client) *CoinGeckoProvider {
+	return &CoinGeckoProvider{
+		client:  client,
+		baseURL: coingeckoBaseURL,
+	}
+}
+
+func (p *CoinGeckoProvider) Name() string {
+	return "coingecko"
+}
+
+// resolveIDs maps ticker symbols to CoinGecko coin ids, refreshing its cache
+// of /coins/list at most once per coingeckoIDCacheTTL. Symbols CoinGecko
+// doesn't list are silently dropped, the same way a provider-specific
+// numeric id lookup would drop an unknown token.
+func (p *CoinGeckoProvider) resolveIDs(symbols []string) ([]string, error) {
+	p.idCacheMu.Lock()
+	defer p.idCacheMu.Unlock()
+
+	if p.idCache == nil || time.Since(p.idCachedAt) > coingeckoIDCacheTTL {
+		cache, err := p.fetchIDCache()
+		if err != nil {
+			if p.idCache == nil {
+				return nil, err
+			}
+			// Serve the stale cache rather than failing outright - a listing
+			// a few hours old is still almost certainly right.
+		} else {
+			p.idCache = cache
+			p.idCachedAt = time.Now()
+		}
+	}
+
+	ids := make([]string, 0, len(symbols))
+	for _, sym := range symbols {
+		if id, ok := p.idCache[strings.ToUpper(sym)]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (p *CoinGeckoProvider) fetchIDCache() (map[string]string, error) {
+	var cache map[string]string
+	err := withRetry(func(attempt int) (time.Duration, error) {
+		resp, err := p.client.Get(p.baseURL + "/coins/list")
+		if err != nil {
+			return 0, fmt.Errorf("coingecko: could not list coins: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return parseRetryAfter(resp.Header.Get("Retry-After")), fmt.Errorf("coingecko: coin list rate limited")
+		}
+		if resp.StatusCode != http.StatusOK {
+			return 0, fmt.Errorf("coingecko: coin list returned status %d", resp.StatusCode)
+		}
+
+		var listings []coingeckoListing
+		if err := json.NewDecoder(resp.Body).Decode(&listings); err != nil {
+			return 0, fmt.Errorf("coingecko: could not decode coin list: %w", err)
+		}
+
+		cache = make(map[string]string, len(listings))
+		for _, l := range listings {
+			symbol := strings.ToUpper(l.Symbol)
+			if _, exists := cache[symbol]; exists {
+				continue // first (alphabetically-by-id) match wins on symbol clashes
+			}
+			cache[symbol] = l.ID
+		}
+		return 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cache, nil
+}
+
+func (p *CoinGeckoProvider) Quotes(symbols []string, convert string) (map[string]Quote, error) {
+	if len(symbols) == 0 {
+		return map[string]Quote{}, nil
+	}
+
+	ids, err := p.resolveIDs(symbols)
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return map[string]Quote{}, nil
+	}
+
+	req, err := http.NewRequest("GET", p.baseURL+"/coins/markets", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	q := url.Values{}
+	q.Add("vs_currency", strings.ToLower(convert))
+	q.Add("ids", strings.Join(ids, ","))
+	q.Add("price_change_percentage", "1h,24h,7d")
+	req.URL.RawQuery = q.Encode()
+	req.Header.Set("Accepts", "application/json")
+
+	var quotes map[string]Quote
+	err = withRetry(func(attempt int) (time.Duration, error) {
+		resp, err := p.client.Do(req)
+		if err != nil {
+			return 0, fmt.Errorf("coingecko: request failed: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return parseRetryAfter(resp.Header.Get("Retry-After")), fmt.Errorf("coingecko: rate limited (HTTP 429)")
+		}
+		if resp.StatusCode != http.StatusOK {
+			return 0, fmt.Errorf("coingecko: request returned status %d", resp.StatusCode)
+		}
+
+		var markets []coingeckoMarket
+		if err := json.NewDecoder(resp.Body).Decode(&markets); err != nil {
+			return 0, fmt.Errorf("coingecko: could not decode response body: %w", err)
+		}
+
+		quotes = make(map[string]Quote, len(markets))
+		for _, m := range markets {
+			symbol := strings.ToUpper(m.Symbol)
+			quotes[symbol] = Quote{
+				ID:        m.ID,
+				Symbol:    symbol,
+				Name:      m.Name,
+				Price:     m.CurrentPrice,
+				Volume24h: m.TotalVolume,
+				// VolumeChange24h is left zero: /coins/markets has no
+				// 24h-volume-% field. Volume-spike alerts guard on
+				// Quote.Source and skip rather than firing on this.
+				PercentChange1h:  m.PriceChangePercentage1hInCur,
+				PercentChange24h: m.PriceChangePercentage24hCur,
+				PercentChange7d:  m.PriceChangePercentage7dInCur,
+				MarketCap:        m.MarketCap,
+				LastUpdated:      m.LastUpdated,
+			}
+		}
+		return 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return quotes, nil
+}
+
+// Ping hits CoinGecko's /ping endpoint, which is free and rate-limit exempt.
+func (p *CoinGeckoProvider) Ping() error {
+	return withRetry(func(attempt int) (time.Duration, error) {
+		resp, err := p.client.Get(p.baseURL + "/ping")
+		if err != nil {
+			return 0, fmt.Errorf("coingecko: ping failed: %w", err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return parseRetryAfter(resp.Header.Get("Retry-After")), fmt.Errorf("coingecko: ping rate limited")
+		}
+		if resp.StatusCode != http.StatusOK {
+			return 0, fmt.Errorf("coingecko: ping returned status %d", resp.StatusCode)
+		}
+		return 0, nil
+	})
+}
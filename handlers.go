@@ -0,0 +1,219 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	tele "gopkg.in/telebot.v3"
+)
+
+// priceMiniChartTimeframe and priceMiniChartCandles control the small chart
+// attached to /price replies - recent detail, not a full window.
+const priceMiniChartTimeframe = Timeframe15m
+const priceMiniChartCandles = 20
+
+// registerHandlers wires up the on-demand commands. The poller keeps running
+// independently and uses the same stores to decide who gets each chart.
+func registerHandlers(b *tele.Bot, store *WatchlistStore, priceStore *PriceStore, alertStore *AlertStore, chartCfgStore *ChartConfigStore, primary, fallback PriceProvider) {
+	b.Handle("/price", func(c tele.Context) error {
+		symbols := c.Args()
+		if len(symbols) == 0 {
+			return c.Send("Usage: /price BTC ETH")
+		}
+
+		quotes, err := fetchQuotes(primary, fallback, symbols, "USD")
+		if err != nil {
+			log.WithError(err).Error("could not fetch quotes for /price")
+			return c.Send("Sorry, couldn't reach any price provider right now.")
+		}
+
+		var reply strings.Builder
+		for _, symbol := range symbols {
+			symbol = strings.ToUpper(symbol)
+			quote, ok := quotes[symbol]
+			if !ok {
+				reply.WriteString(fmt.Sprintf("%s: no data\n\n", symbol))
+				continue
+			}
+			reply.WriteString(buildPriceString(
+				quote.Name,
+				quote.Price,
+				quote.PercentChange1h,
+				quote.PercentChange24h,
+				quote.PercentChange7d,
+			))
+		}
+		return sendQuoteReply(c, priceStore, chartCfgStore, reply.String(), symbols)
+	})
+
+	b.Handle("/chart", func(c tele.Context) error {
+		args := c.Args()
+		if len(args) < 1 {
+			return c.Send("Usage: /chart SOL [1m|15m|1h|1d]")
+		}
+
+		symbol := strings.ToUpper(args[0])
+		tf := priceMiniChartTimeframe
+		if len(args) >= 2 {
+			parsed, err := ParseTimeframe(args[1])
+			if err != nil {
+				return c.Send("Unknown timeframe, try one of: 1m, 15m, 1h, 1d")
+			}
+			tf = parsed
+		}
+
+		chatID := c.Chat().ID
+		plotName, err := renderChart(priceStore, chartCfgStore.Overlays(chatID), symbol, tf, 100, fmt.Sprintf(".%d", chatID))
+		if err != nil {
+			return c.Send(fmt.Sprintf("No chart data for %s yet.", symbol))
+		}
+		return c.Send(&tele.Photo{File: tele.FromDisk(plotName)})
+	})
+
+	b.Handle("/chartcfg", func(c tele.Context) error {
+		args := c.Args()
+		if len(args) < 1 {
+			return c.Send("Usage: /chartcfg ADD|RM|LIST [sma|ema|bollinger|rsi]")
+		}
+
+		chatID := c.Chat().ID
+		switch strings.ToUpper(args[0]) {
+		case "ADD":
+			if len(args) < 2 || !isOverlay(strings.ToLower(args[1])) {
+				return c.Send("Usage: /chartcfg ADD sma|ema|bollinger|rsi")
+			}
+			overlay := Overlay(strings.ToLower(args[1]))
+			if err := chartCfgStore.Add(chatID, overlay); err != nil {
+				log.WithError(err).Error("could not save chart config")
+				return c.Send("Couldn't save that, try again.")
+			}
+			return c.Send(fmt.Sprintf("Added %s to this chat's chart overlays.", overlay))
+
+		case "RM":
+			if len(args) < 2 || !isOverlay(strings.ToLower(args[1])) {
+				return c.Send("Usage: /chartcfg RM sma|ema|bollinger|rsi")
+			}
+			overlay := Overlay(strings.ToLower(args[1]))
+			if err := chartCfgStore.Remove(chatID, overlay); err != nil {
+				log.WithError(err).Error("could not save chart config")
+				return c.Send("Couldn't save that, try again.")
+			}
+			return c.Send(fmt.Sprintf("Removed %s from this chat's chart overlays.", overlay))
+
+		case "LIST":
+			overlays := chartCfgStore.Overlays(chatID)
+			if len(overlays) == 0 {
+				return c.Send("No overlays enabled. Try /chartcfg ADD sma")
+			}
+			return c.Send("Overlays: " + formatOverlayList(overlays))
+
+		default:
+			return c.Send("Usage: /chartcfg ADD|RM|LIST [sma|ema|bollinger|rsi]")
+		}
+	})
+
+	b.Handle("/watch", func(c tele.Context) error {
+		args := c.Args()
+		if len(args) < 1 {
+			return c.Send("Usage: /watch ADD|RM|LIST [SYMBOL]")
+		}
+
+		chatID := c.Chat().ID
+		switch strings.ToUpper(args[0]) {
+		case "ADD":
+			if len(args) < 2 {
+				return c.Send("Usage: /watch ADD SOL")
+			}
+			symbol := strings.ToUpper(args[1])
+			if err := store.Add(chatID, symbol); err != nil {
+				log.WithError(err).Error("could not save watchlist")
+				return c.Send("Couldn't save that, try again.")
+			}
+			return c.Send(fmt.Sprintf("Added %s to this chat's watchlist.", symbol))
+
+		case "RM":
+			if len(args) < 2 {
+				return c.Send("Usage: /watch RM SOL")
+			}
+			symbol := strings.ToUpper(args[1])
+			if err := store.Remove(chatID, symbol); err != nil {
+				log.WithError(err).Error("could not save watchlist")
+				return c.Send("Couldn't save that, try again.")
+			}
+			return c.Send(fmt.Sprintf("Removed %s from this chat's watchlist.", symbol))
+
+		case "LIST":
+			symbols := store.List(chatID)
+			if len(symbols) == 0 {
+				return c.Send("This chat isn't watching anything yet. Try /watch ADD SOL")
+			}
+			return c.Send("Watching: " + strings.Join(symbols, ", "))
+
+		default:
+			return c.Send("Usage: /watch ADD|RM|LIST [SYMBOL]")
+		}
+	})
+
+	b.Handle("/alert", func(c tele.Context) error {
+		args := c.Args()
+		if len(args) < 1 {
+			return c.Send("Usage: /alert SYMBOL > THRESHOLD | /alert rm ID")
+		}
+
+		chatID := c.Chat().ID
+		if strings.EqualFold(args[0], "rm") {
+			if len(args) < 2 {
+				return c.Send("Usage: /alert rm ID")
+			}
+			id, err := strconv.ParseInt(args[1], 10, 64)
+			if err != nil {
+				return c.Send("Alert id must be a number.")
+			}
+			if err := alertStore.Remove(chatID, id); err != nil {
+				return c.Send(err.Error())
+			}
+			return c.Send(fmt.Sprintf("Removed alert #%d.", id))
+		}
+
+		alert, err := parseAlertRule(chatID, args)
+		if err != nil {
+			return c.Send(err.Error())
+		}
+		id, err := alertStore.Add(alert)
+		if err != nil {
+			log.WithError(err).Error("could not save alert")
+			return c.Send("Couldn't save that, try again.")
+		}
+		return c.Send(fmt.Sprintf("Set alert #%d: %s", id, alert.String()))
+	})
+
+	b.Handle("/alerts", func(c tele.Context) error {
+		alerts := alertStore.ListForChat(c.Chat().ID)
+		if len(alerts) == 0 {
+			return c.Send("No alerts set for this chat. Try /alert SOL > 200")
+		}
+		var reply strings.Builder
+		for _, a := range alerts {
+			reply.WriteString(a.String())
+			reply.WriteString("\n")
+		}
+		return c.Send(reply.String())
+	})
+}
+
+// sendQuoteReply sends the formatted quote text, attaching a mini chart for
+// the first symbol we have chart history for.
+func sendQuoteReply(c tele.Context, priceStore *PriceStore, chartCfgStore *ChartConfigStore, text string, symbols []string) error {
+	chatID := c.Chat().ID
+	overlays := chartCfgStore.Overlays(chatID)
+	for _, symbol := range symbols {
+		symbol = strings.ToUpper(symbol)
+		plotName, err := renderChart(priceStore, overlays, symbol, priceMiniChartTimeframe, priceMiniChartCandles, fmt.Sprintf(".%d", chatID))
+		if err == nil {
+			return c.Send(&tele.Photo{File: tele.FromDisk(plotName), Caption: text})
+		}
+	}
+	return c.Send(text)
+}
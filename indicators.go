@@ -0,0 +1,170 @@
+package main
+
+import (
+	"math"
+
+	"gonum.org/v1/plot/plotter"
+)
+
+// Overlay is a technical indicator that can be drawn on top of a chart.
+// Which ones are active is per-chat, configured via /chartcfg.
+type Overlay string
+
+const (
+	OverlaySMA       Overlay = "sma"
+	OverlayEMA       Overlay = "ema"
+	OverlayBollinger Overlay = "bollinger"
+	OverlayRSI       Overlay = "rsi"
+)
+
+var allOverlays = []Overlay{OverlaySMA, OverlayEMA, OverlayBollinger, OverlayRSI}
+
+func isOverlay(s string) bool {
+	for _, o := range allOverlays {
+		if string(o) == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Indicator windows. These match the conventions named in the overlay spec:
+// SMA/EMA/Bollinger over 20 candles, RSI over 14 using Wilder's smoothing.
+const (
+	smaWindow       = 20
+	emaWindow       = 20
+	bollingerWindow = 20
+	bollingerK      = 2.0
+	rsiWindow       = 14
+)
+
+// closesOf extracts the closing price series from a candle slice, in the
+// same order, for feeding to the indicator calculations below.
+func closesOf(candles []Candle) []float64 {
+	closes := make([]float64, len(candles))
+	for i, c := range candles {
+		closes[i] = c.Close
+	}
+	return closes
+}
+
+// smaSeries returns the simple moving average of closes over n periods,
+// aligned to the same x index as the input. It returns nil when there
+// aren't enough points for even one window, so callers can skip the
+// overlay instead of drawing a misleading partial line.
+func smaSeries(closes []float64, n int) plotter.XYs {
+	if len(closes) < n {
+		return nil
+	}
+	pts := make(plotter.XYs, 0, len(closes)-n+1)
+	sum := 0.0
+	for i, price := range closes {
+		sum += price
+		if i >= n {
+			sum -= closes[i-n]
+		}
+		if i >= n-1 {
+			pts = append(pts, struct{ X, Y float64 }{float64(i), sum / float64(n)})
+		}
+	}
+	return pts
+}
+
+// emaSeries returns the exponential moving average of closes over n
+// periods, seeded with SMA(n) as EMA0 per the standard convention.
+func emaSeries(closes []float64, n int) plotter.XYs {
+	if len(closes) < n {
+		return nil
+	}
+	alpha := 2.0 / (float64(n) + 1)
+
+	seed := 0.0
+	for _, price := range closes[:n] {
+		seed += price
+	}
+	seed /= float64(n)
+
+	pts := make(plotter.XYs, 0, len(closes)-n+1)
+	pts = append(pts, struct{ X, Y float64 }{float64(n - 1), seed})
+
+	ema := seed
+	for i := n; i < len(closes); i++ {
+		ema = alpha*closes[i] + (1-alpha)*ema
+		pts = append(pts, struct{ X, Y float64 }{float64(i), ema})
+	}
+	return pts
+}
+
+// bollingerBands returns the rolling-mean midline and the upper/lower bands
+// at mean +/- k*stddev over an n-period window.
+func bollingerBands(closes []float64, n int, k float64) (mid, upper, lower plotter.XYs) {
+	if len(closes) < n {
+		return nil, nil, nil
+	}
+	for i := n - 1; i < len(closes); i++ {
+		window := closes[i-n+1 : i+1]
+		mean := 0.0
+		for _, price := range window {
+			mean += price
+		}
+		mean /= float64(n)
+
+		variance := 0.0
+		for _, price := range window {
+			variance += (price - mean) * (price - mean)
+		}
+		stddev := math.Sqrt(variance / float64(n))
+
+		x := float64(i)
+		mid = append(mid, struct{ X, Y float64 }{x, mean})
+		upper = append(upper, struct{ X, Y float64 }{x, mean + k*stddev})
+		lower = append(lower, struct{ X, Y float64 }{x, mean - k*stddev})
+	}
+	return mid, upper, lower
+}
+
+// rsiSeries computes RSI(14) using Wilder's smoothing: the first
+// avgGain/avgLoss are the simple mean of the first window gains/losses,
+// then each subsequent step folds in the new gain/loss at weight 1/window.
+func rsiSeries(closes []float64) plotter.XYs {
+	if len(closes) < rsiWindow+1 {
+		return nil
+	}
+
+	var avgGain, avgLoss float64
+	for i := 1; i <= rsiWindow; i++ {
+		delta := closes[i] - closes[i-1]
+		if delta > 0 {
+			avgGain += delta
+		} else {
+			avgLoss += -delta
+		}
+	}
+	avgGain /= rsiWindow
+	avgLoss /= rsiWindow
+
+	pts := make(plotter.XYs, 0, len(closes)-rsiWindow)
+	pts = append(pts, struct{ X, Y float64 }{float64(rsiWindow), rsiFromAverages(avgGain, avgLoss)})
+
+	for i := rsiWindow + 1; i < len(closes); i++ {
+		delta := closes[i] - closes[i-1]
+		gain, loss := 0.0, 0.0
+		if delta > 0 {
+			gain = delta
+		} else {
+			loss = -delta
+		}
+		avgGain = (avgGain*(rsiWindow-1) + gain) / rsiWindow
+		avgLoss = (avgLoss*(rsiWindow-1) + loss) / rsiWindow
+		pts = append(pts, struct{ X, Y float64 }{float64(i), rsiFromAverages(avgGain, avgLoss)})
+	}
+	return pts
+}
+
+func rsiFromAverages(avgGain, avgLoss float64) float64 {
+	if avgLoss == 0 {
+		return 100
+	}
+	rs := avgGain / avgLoss
+	return 100 - 100/(1+rs)
+}
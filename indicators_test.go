@@ -0,0 +1,90 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func xysApproxEqual(t *testing.T, got, want []struct{ X, Y float64 }, tolerance float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d points, want %d: got=%v want=%v", len(got), len(want), got, want)
+	}
+	for i := range got {
+		if got[i].X != want[i].X || math.Abs(got[i].Y-want[i].Y) > tolerance {
+			t.Fatalf("point %d: got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSMASeries(t *testing.T) {
+	closes := []float64{1, 2, 3, 4, 5}
+	pts := smaSeries(closes, 3)
+	want := []struct{ X, Y float64 }{{2, 2}, {3, 3}, {4, 4}}
+	xysApproxEqual(t, []struct{ X, Y float64 }(pts), want, 1e-9)
+
+	if pts := smaSeries(closes, len(closes)+1); pts != nil {
+		t.Fatalf("expected nil for a window longer than the series, got %v", pts)
+	}
+}
+
+func TestEMASeries(t *testing.T) {
+	closes := []float64{1, 2, 3, 4, 5}
+	pts := emaSeries(closes, 3)
+	// alpha = 2/(3+1) = 0.5; seeded with SMA(3) = mean(1,2,3) = 2.
+	want := []struct{ X, Y float64 }{{2, 2}, {3, 3}, {4, 4}}
+	xysApproxEqual(t, []struct{ X, Y float64 }(pts), want, 1e-9)
+
+	if pts := emaSeries(closes, len(closes)+1); pts != nil {
+		t.Fatalf("expected nil for a window longer than the series, got %v", pts)
+	}
+}
+
+func TestBollingerBands(t *testing.T) {
+	closes := []float64{1, 2, 3, 4, 5}
+	mid, upper, lower := bollingerBands(closes, 3, 2.0)
+
+	wantMid := []struct{ X, Y float64 }{{2, 2}, {3, 3}, {4, 4}}
+	xysApproxEqual(t, []struct{ X, Y float64 }(mid), wantMid, 1e-9)
+
+	// Each 3-point window here has the same population stddev (sqrt(2/3)).
+	stddev := math.Sqrt(2.0 / 3.0)
+	wantUpper := []struct{ X, Y float64 }{{2, 2 + 2*stddev}, {3, 3 + 2*stddev}, {4, 4 + 2*stddev}}
+	wantLower := []struct{ X, Y float64 }{{2, 2 - 2*stddev}, {3, 3 - 2*stddev}, {4, 4 - 2*stddev}}
+	xysApproxEqual(t, []struct{ X, Y float64 }(upper), wantUpper, 1e-9)
+	xysApproxEqual(t, []struct{ X, Y float64 }(lower), wantLower, 1e-9)
+
+	if mid, upper, lower := bollingerBands(closes, len(closes)+1, 2.0); mid != nil || upper != nil || lower != nil {
+		t.Fatalf("expected nil bands for a window longer than the series, got mid=%v upper=%v lower=%v", mid, upper, lower)
+	}
+}
+
+func TestRSISeriesMonotonicIncrease(t *testing.T) {
+	// 16 strictly increasing closes by 1: every delta is a +1 gain and
+	// there are no losses, so Wilder's avgLoss stays 0 and RSI pins at 100.
+	closes := make([]float64, 16)
+	for i := range closes {
+		closes[i] = float64(i + 1)
+	}
+
+	pts := rsiSeries(closes)
+	want := []struct{ X, Y float64 }{{14, 100}, {15, 100}}
+	xysApproxEqual(t, []struct{ X, Y float64 }(pts), want, 1e-9)
+}
+
+func TestRSISeriesTooShort(t *testing.T) {
+	closes := make([]float64, rsiWindow)
+	if pts := rsiSeries(closes); pts != nil {
+		t.Fatalf("expected nil for fewer than rsiWindow+1 closes, got %v", pts)
+	}
+}
+
+func TestRSIFromAverages(t *testing.T) {
+	if got := rsiFromAverages(1, 0); got != 100 {
+		t.Fatalf("avgLoss=0 should give RSI 100, got %v", got)
+	}
+	// avgGain == avgLoss means RS == 1, so RSI == 50.
+	if got := rsiFromAverages(1, 1); math.Abs(got-50) > 1e-9 {
+		t.Fatalf("equal averages should give RSI 50, got %v", got)
+	}
+}
@@ -1,87 +1,32 @@
 package main
 
 import (
-	"bufio"
-	"encoding/json"
 	"fmt"
-	"image/color"
 	"math"
-	"net/http"
-	"net/url"
 	"os"
+	"os/signal"
 	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
-	"gonum.org/v1/plot"
-	"gonum.org/v1/plot/plotter"
-	"gonum.org/v1/plot/vg"
-
 	"github.com/joho/godotenv"
 	log "github.com/sirupsen/logrus"
 	tele "gopkg.in/telebot.v3"
 	"gopkg.in/telebot.v3/middleware"
 )
 
-const eth = "1027"
-const sol = "5426"
-const pip = "34625"
-
-type Status struct {
-	Timestamp    string  `json:"timestamp"`
-	ErrorCode    int     `json:"error_code"`
-	ErrorMessage *string `json:"error_message"`
-	Elapsed      int     `json:"elapsed"`
-	CreditCount  int     `json:"credit_count"`
-	Notice       *string `json:"notice"`
-}
-
-type Quote struct {
-	Price                 float64 `json:"price"`
-	Volume24h             float64 `json:"volume_24h"`
-	VolumeChange24h       float64 `json:"volume_change_24h"`
-	PercentChange1h       float64 `json:"percent_change_1h"`
-	PercentChange24h      float64 `json:"percent_change_24h"`
-	PercentChange7d       float64 `json:"percent_change_7d"`
-	PercentChange30d      float64 `json:"percent_change_30d"`
-	PercentChange60d      float64 `json:"percent_change_60d"`
-	PercentChange90d      float64 `json:"percent_change_90d"`
-	MarketCap             float64 `json:"market_cap"`
-	MarketCapDominance    float64 `json:"market_cap_dominance"`
-	FullyDilutedMarketCap float64 `json:"fully_diluted_market_cap"`
-	Tvl                   *string `json:"tvl"`
-	LastUpdated           string  `json:"last_updated"`
-}
+// broadcastTimeframe is the candle width charted on every poll cycle when
+// pushing unsolicited updates to subscribed chats. Users can ask for other
+// windows on demand with /chart SYMBOL 1h.
+const broadcastTimeframe = Timeframe15m
 
-type CryptoData struct {
-	ID                            int              `json:"id"`
-	Name                          string           `json:"name"`
-	Symbol                        string           `json:"symbol"`
-	Slug                          string           `json:"slug"`
-	NumMarketPairs                int              `json:"num_market_pairs"`
-	DateAdded                     string           `json:"date_added"`
-	Tags                          []string         `json:"tags"`
-	MaxSupply                     *float64         `json:"max_supply"`
-	CirculatingSupply             float64          `json:"circulating_supply"`
-	TotalSupply                   float64          `json:"total_supply"`
-	IsActive                      int              `json:"is_active"`
-	InfiniteSupply                bool             `json:"infinite_supply"`
-	Platform                      *string          `json:"platform"`
-	CmcRank                       int              `json:"cmc_rank"`
-	IsFiat                        int              `json:"is_fiat"`
-	SelfReportedCirculatingSupply *float64         `json:"self_reported_circulating_supply"`
-	SelfReportedMarketCap         *float64         `json:"self_reported_market_cap"`
-	TvlRatio                      *string          `json:"tvl_ratio"`
-	LastUpdated                   string           `json:"last_updated"`
-	Quote                         map[string]Quote `json:"quote"`
-}
-
-type Response struct {
-	Status Status                `json:"status"`
-	Data   map[string]CryptoData `json:"data"`
-}
+// trackedSymbols are the tokens the poller falls back to fetching and
+// recording when no chat has a watchlist or alert set yet. No chart is sent
+// for them then, since no chat is subscribed.
+var trackedSymbols = []string{"ETH", "SOL", "PIP"}
 
 func roundToPrecision(num float64, precision int) float64 {
 	factor := math.Pow(10, float64(precision))
@@ -110,144 +55,139 @@ func buildPriceString(token string, price float64, change1h float64, change24h f
 	return priceStr.String()
 }
 
-func fetchPoints(symbolName string, fname string) plotter.XYs {
-	file, err := os.Open(fname)
-	if err != nil {
-		panic(err)
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	const max = 100
-	contents := []string{}
-
-	for scanner.Scan() {
-		line := scanner.Text()
-		if len(contents) >= max {
-			contents = contents[1:]
-		}
-		contents = append(contents, line)
-	}
-	pts := make(plotter.XYs, len(contents))
-	for i := range pts {
-		data := strings.Split(contents[i], "|")
-		price, err := strconv.ParseFloat(data[1], 10)
-		if err != nil {
-			panic(err)
-		}
-		pts[i].X = float64(i)
-		pts[i].Y = float64(price)
-	}
-
-	// overwrite old contents with last 20 lines
-	writer := bufio.NewWriter(file)
-	for _, line := range contents {
-		_, err := writer.WriteString(line + "\n")
-		if err != nil {
-			panic(err)
-		}
-	}
-	return pts
-}
-
-func pollApi(bot *tele.Bot, qChan chan bool, wg *sync.WaitGroup) {
+func pollApi(bot *tele.Bot, store *WatchlistStore, priceStore *PriceStore, alertStore *AlertStore, chartCfgStore *ChartConfigStore, qChan chan bool, wg *sync.WaitGroup) {
 	defer wg.Done()
-	chanId, err := strconv.ParseInt(os.Getenv("CHANNEL_ID"), 10, 64)
-	if err != nil {
-		log.Fatal(err)
-		return
-	}
-	chat, err := bot.ChatByID(chanId)
-	if err != nil {
-		log.Fatal(err)
-		return
-	}
+
+	primary, fallback := newPriceProvider()
 
 	for {
 		select {
 		case <-qChan:
 			return
 		default:
-			client := &http.Client{}
-			log.Warn("GET")
-			req, err := http.NewRequest("GET", "https://pro-api.coinmarketcap.com/v1/cryptocurrency/quotes/latest", nil)
-			if err != nil {
-				log.Print(err)
-				os.Exit(1)
+			symbols := unionSymbols(store.Symbols(), alertStore.Symbols())
+			if len(symbols) == 0 {
+				symbols = trackedSymbols
 			}
 
-			q := url.Values{}
-			q.Add("id", fmt.Sprintf("%s,%s,%s", eth, sol, pip))
-			q.Add("convert", "USD")
-
-			req.Header.Set("Accepts", "application/json")
-			req.Header.Add("X-CMC_PRO_API_KEY", os.Getenv("CMC_TOKEN"))
-			req.URL.RawQuery = q.Encode()
-
-			resp, err := client.Do(req)
+			log.WithField("provider", primary.Name()).Warn("GET")
+			quotes, err := fetchQuotes(primary, fallback, symbols, "USD")
 			if err != nil {
-				fmt.Println("Error sending request to server")
-				os.Exit(1)
-			}
-			var resData Response
-			err = json.NewDecoder(resp.Body).Decode(&resData)
-			if err != nil {
-				log.Fatal("Could not decode response body")
-				return
+				log.WithError(err).Error("could not fetch quotes from any provider")
+				if sleepOrShutdown(qChan, time.Minute*15) {
+					return
+				}
+				continue
 			}
 
-			keys := make([]string, 0, len(resData.Data))
-			for key := range resData.Data {
-				keys = append(keys, key)
+			fetchedSymbols := make([]string, 0, len(quotes))
+			for symbol := range quotes {
+				fetchedSymbols = append(fetchedSymbols, symbol)
 			}
-			sort.Strings(keys)
+			sort.Strings(fetchedSymbols)
 
-			for _, key := range keys {
-				value := resData.Data[key]
-				fname := fmt.Sprintf(".data.%s", key)
+			now := time.Now()
+			for _, symbol := range fetchedSymbols {
+				value := quotes[symbol]
 
-				f, err := os.OpenFile(fname, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0600)
-				if err != nil {
-					panic(err)
+				if err := priceStore.WritePrice(symbol, now, value.Price); err != nil {
+					log.WithError(err).WithField("symbol", symbol).Error("could not record price")
+					continue
 				}
-				defer f.Close()
-
-				p := plot.New()
 
-				p.Title.Text = value.Name
-				p.X.Label.Text = "Time"
-				p.Y.Label.Text = "Price, USD"
-
-				t := time.Now()
-				tFmt := t.Format("2006-01-02 15:00:00")
-
-				lastPrice := fmt.Sprintf("%s|%.2f\n", tFmt, value.Quote["USD"].Price)
-				if _, err = f.WriteString(lastPrice); err != nil {
-					panic(err)
-				}
 				resStr := buildPriceString(
 					value.Name,
-					value.Quote["USD"].Price,
-					value.Quote["USD"].PercentChange1h,
-					value.Quote["USD"].PercentChange24h,
-					value.Quote["USD"].PercentChange7d,
+					value.Price,
+					value.PercentChange1h,
+					value.PercentChange24h,
+					value.PercentChange7d,
 				)
 
-				line, points, err := plotter.NewLinePoints(fetchPoints(value.Name, fname))
-				p.Add(line, points)
+				chats := store.ChatsFor(symbol)
+				for _, chatID := range chats {
+					chat, err := bot.ChatByID(chatID)
+					if err != nil {
+						log.WithError(err).WithField("chat_id", chatID).Warn("could not resolve chat")
+						continue
+					}
+
+					overlays := chartCfgStore.Overlays(chatID)
+					plotName, err := renderChart(priceStore, overlays, symbol, broadcastTimeframe, 100, fmt.Sprintf(".%d", chatID))
+					if err != nil {
+						log.WithError(err).WithField("symbol", symbol).Warn("could not render chart")
+						bot.Send(chat, resStr)
+						continue
+					}
+					plotPhoto := &tele.Photo{File: tele.FromDisk(plotName), Caption: resStr}
+					bot.Send(chat, plotPhoto)
+				}
 
-				line.Color = color.RGBA{G: 255, A: 255}
+				evaluateAlerts(bot, alertStore, priceStore, symbol, value, now)
+			}
 
-				plotName := fmt.Sprintf("%s.png", key)
-				if err := p.Save(6*vg.Inch, 6*vg.Inch, plotName); err != nil {
-					log.Fatal(err)
-					return
-				}
-				plotPhoto := &tele.Photo{File: tele.FromDisk(plotName), Caption: resStr}
-				bot.Send(chat, plotPhoto)
+			if sleepOrShutdown(qChan, time.Minute*15) {
+				return
 			}
+		}
+	}
+}
 
-			time.Sleep(time.Minute * 15)
+// unionSymbols merges symbol lists from multiple sources (watchlists,
+// alerts) into a single deduplicated, sorted fetch list, so a symbol only
+// an alert cares about still gets polled even if no chat is watching it.
+func unionSymbols(lists ...[]string) []string {
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		for _, sym := range list {
+			seen[strings.ToUpper(sym)] = true
+		}
+	}
+	symbols := make([]string, 0, len(seen))
+	for sym := range seen {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// sleepOrShutdown waits for d, returning early (and reporting true) if qChan
+// is closed first. This keeps shutdown prompt instead of pollApi sitting
+// through the rest of a 15-minute poll interval.
+func sleepOrShutdown(qChan chan bool, d time.Duration) bool {
+	select {
+	case <-qChan:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+
+// evaluateAlerts checks every due alert on symbol against the freshly
+// fetched quote, notifies the owning chat (with the current chart) when one
+// fires, and disarms it to start its cooldown.
+func evaluateAlerts(bot *tele.Bot, alertStore *AlertStore, priceStore *PriceStore, symbol string, quote Quote, now time.Time) {
+	for _, a := range alertStore.Due(now) {
+		if a.Symbol != symbol {
+			continue
+		}
+		fired, message := a.Evaluate(quote)
+		if !fired {
+			continue
+		}
+
+		chat, err := bot.ChatByID(a.ChatID)
+		if err != nil {
+			log.WithError(err).WithField("chat_id", a.ChatID).Warn("could not resolve chat for alert")
+			continue
+		}
+		if plotName, err := renderChart(priceStore, nil, symbol, broadcastTimeframe, 100, ".alert"); err == nil {
+			bot.Send(chat, &tele.Photo{File: tele.FromDisk(plotName), Caption: message})
+		} else {
+			bot.Send(chat, message)
+		}
+
+		if err := alertStore.MarkFired(a.ID, now); err != nil {
+			log.WithError(err).WithField("alert_id", a.ID).Warn("could not mark alert as fired")
 		}
 	}
 }
@@ -281,9 +221,68 @@ func main() {
 	}
 	b.Use(middleware.Logger())
 
+	store, err := NewWatchlistStore(defaultWatchlistPath)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	seedDefaultWatchlist(store)
+
+	priceStore, err := NewPriceStore(defaultStorePath)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+	defer priceStore.Close()
+
+	alertStore, err := NewAlertStore(defaultAlertsPath)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+
+	chartCfgStore, err := NewChartConfigStore(defaultChartCfgPath)
+	if err != nil {
+		log.Fatal(err)
+		return
+	}
+
+	primary, fallback := newPriceProvider()
+	registerHandlers(b, store, priceStore, alertStore, chartCfgStore, primary, fallback)
+
 	var wg sync.WaitGroup
 	wg.Add(1)
 	qChan := make(chan (bool))
-	go pollApi(b, qChan, &wg)
+	go pollApi(b, store, priceStore, alertStore, chartCfgStore, qChan, &wg)
+	go b.Start()
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigChan
+		log.WithField("signal", sig).Warn("shutting down")
+		close(qChan)
+		b.Stop()
+	}()
+
 	wg.Wait()
 }
+
+// seedDefaultWatchlist preserves the old single-channel behavior for
+// deployments that only set CHANNEL_ID and haven't adopted per-chat
+// watchlists yet.
+func seedDefaultWatchlist(store *WatchlistStore) {
+	chanId, err := strconv.ParseInt(os.Getenv("CHANNEL_ID"), 10, 64)
+	if err != nil {
+		return
+	}
+	if len(store.List(chanId)) > 0 {
+		return
+	}
+	for _, symbol := range trackedSymbols {
+		if err := store.Add(chanId, symbol); err != nil {
+			log.WithError(err).Warn("could not seed default watchlist")
+			return
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// maxProviderAttempts bounds how many times a provider retries a single
+// request before giving up and letting fetchQuotes fall back to the other
+// provider.
+const maxProviderAttempts = 4
+
+// requestAttempt is one try at a provider request. It returns retryAfter
+// when the upstream told us how long to back off (e.g. a 429's Retry-After
+// header); a zero value means "use the default exponential backoff".
+type requestAttempt func(attempt int) (retryAfter time.Duration, err error)
+
+// withRetry runs attempt up to maxProviderAttempts times, waiting between
+// tries with exponential backoff plus jitter (or the upstream-supplied
+// retryAfter, when given). This is what keeps a transient 5xx or a rate
+// limit from taking a provider down for the rest of the poll cycle.
+func withRetry(attempt requestAttempt) error {
+	var lastErr error
+	for i := 0; i < maxProviderAttempts; i++ {
+		retryAfter, err := attempt(i)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if i == maxProviderAttempts-1 {
+			break
+		}
+		wait := retryAfter
+		if wait <= 0 {
+			wait = backoffWithJitter(i)
+		}
+		time.Sleep(wait)
+	}
+	return lastErr
+}
+
+// backoffWithJitter returns 2^attempt seconds plus up to half that again in
+// jitter, so concurrent retries (e.g. across symbols) don't all land on the
+// upstream at the same instant.
+func backoffWithJitter(attempt int) time.Duration {
+	base := time.Duration(1<<uint(attempt)) * time.Second
+	jitter := time.Duration(rand.Int63n(int64(base)/2 + 1))
+	return base + jitter
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds. It returns 0
+// (meaning "fall back to exponential backoff") if the header is absent or
+// not a plain integer.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// Quote is the provider-agnostic price snapshot the rest of the bot works
+// with. Each PriceProvider implementation is responsible for mapping its own
+// upstream response shape onto this struct.
+type Quote struct {
+	ID               string
+	Symbol           string
+	Name             string
+	Price            float64
+	Volume24h        float64
+	VolumeChange24h  float64
+	PercentChange1h  float64
+	PercentChange24h float64
+	PercentChange7d  float64
+	MarketCap        float64
+	LastUpdated      string
+	// Source is the provider name (e.g. "cmc", "coingecko") that returned
+	// this quote. Callers use it to gate features a provider doesn't
+	// support - CoinGecko's /coins/markets has no 24h-volume-% field, so
+	// VolumeChange24h is always zero on quotes sourced from it.
+	Source string
+}
+
+// PriceProvider is implemented by anything that can fetch current quotes for
+// a set of tokens. Symbols are the usual ticker symbols (e.g. "BTC", "ETH") -
+// any provider-specific id translation (CMC numeric ids, CoinGecko coin ids)
+// happens inside the implementation, not at the call site, so new symbols
+// work without a code change.
+type PriceProvider interface {
+	// Name identifies the provider, e.g. for logging and env selection.
+	Name() string
+	// Quotes fetches the latest quote for each symbol, keyed by uppercase
+	// symbol. A symbol the provider can't resolve is simply omitted from
+	// the result rather than erroring the whole call.
+	Quotes(symbols []string, convert string) (map[string]Quote, error)
+	// Ping checks that the provider is reachable and not rate/credit limited.
+	Ping() error
+}
+
+// newHTTPClient returns a client with a sane timeout, shared by all providers
+// so we're not paying connection setup cost on every poll.
+func newHTTPClient() *http.Client {
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+// newPriceProvider builds the provider selected by PRICE_PROVIDER (cmc or
+// coingecko, defaulting to cmc) along with the other one to use as a
+// fallback when the primary errors out or runs out of credits.
+func newPriceProvider() (primary PriceProvider, fallback PriceProvider) {
+	client := newHTTPClient()
+	cmcProvider := NewCMCProvider(client)
+	coingeckoProvider := NewCoinGeckoProvider(client)
+
+	switch strings.ToLower(os.Getenv("PRICE_PROVIDER")) {
+	case "coingecko":
+		return coingeckoProvider, cmcProvider
+	default:
+		return cmcProvider, coingeckoProvider
+	}
+}
+
+// fetchQuotes calls the primary provider and transparently falls back to the
+// secondary one if the primary errors (including rate/credit exhaustion,
+// which providers report as an error from Quotes).
+func fetchQuotes(primary, fallback PriceProvider, symbols []string, convert string) (map[string]Quote, error) {
+	quotes, err := primary.Quotes(symbols, convert)
+	if err == nil {
+		return tagSource(quotes, primary.Name()), nil
+	}
+	log.WithError(err).WithField("provider", primary.Name()).Warn("primary price provider failed, falling back")
+
+	quotes, fallbackErr := fallback.Quotes(symbols, convert)
+	if fallbackErr != nil {
+		return nil, fmt.Errorf("primary provider %s failed: %w; fallback provider %s failed: %v", primary.Name(), err, fallback.Name(), fallbackErr)
+	}
+	return tagSource(quotes, fallback.Name()), nil
+}
+
+// tagSource stamps every quote with the provider that produced it, so
+// downstream consumers (e.g. alert evaluation) can tell when a field a
+// provider doesn't populate - like CoinGecko's VolumeChange24h - is
+// genuinely zero versus simply unsupported.
+func tagSource(quotes map[string]Quote, source string) map[string]Quote {
+	for symbol, q := range quotes {
+		q.Source = source
+		quotes[symbol] = q
+	}
+	return quotes
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// defaultStorePath is where the price time-series lives. It replaces the old
+// per-symbol ".data.<symbol>" text files, which could only hold ~100 lines
+// of raw history and had to be re-parsed on every poll.
+const defaultStorePath = ".prices.db"
+
+// Timeframe is one of the OHLC bucket widths the store rolls raw prices
+// into as they're written, so charting a wider window never has to re-scan
+// the full raw history.
+type Timeframe string
+
+const (
+	Timeframe1m  Timeframe = "1m"
+	Timeframe15m Timeframe = "15m"
+	Timeframe1h  Timeframe = "1h"
+	Timeframe1d  Timeframe = "1d"
+)
+
+// timeframeDurations lists the supported timeframes in bucket-width order,
+// used both to drive the OHLC rollup on write and to validate /chart input.
+var timeframeDurations = []struct {
+	name     Timeframe
+	duration time.Duration
+}{
+	{Timeframe1m, time.Minute},
+	{Timeframe15m, 15 * time.Minute},
+	{Timeframe1h, time.Hour},
+	{Timeframe1d, 24 * time.Hour},
+}
+
+// Candle is one OHLC bucket.
+type Candle struct {
+	Timestamp time.Time `json:"ts"`
+	Open      float64   `json:"open"`
+	High      float64   `json:"high"`
+	Low       float64   `json:"low"`
+	Close     float64   `json:"close"`
+}
+
+// PriceStore persists raw price ticks and keeps rolling 1m/15m/1h/1d OHLC
+// candles up to date as new ticks come in.
+type PriceStore struct {
+	db *bolt.DB
+}
+
+func rawBucketName(symbol string) []byte {
+	return []byte("raw:" + symbol)
+}
+
+func ohlcBucketName(symbol string, tf Timeframe) []byte {
+	return []byte(fmt.Sprintf("ohlc:%s:%s", tf, symbol))
+}
+
+// NewPriceStore opens (or creates) the bbolt-backed store at path.
+func NewPriceStore(path string) (*PriceStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("store: could not open %s: %w", path, err)
+	}
+	return &PriceStore{db: db}, nil
+}
+
+func (s *PriceStore) Close() error {
+	return s.db.Close()
+}
+
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.Unix()))
+	return key
+}
+
+// WritePrice records a single price tick for symbol at ts and rolls it into
+// every tracked OHLC timeframe.
+func (s *PriceStore) WritePrice(symbol string, ts time.Time, price float64) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		rawBucket, err := tx.CreateBucketIfNotExists(rawBucketName(symbol))
+		if err != nil {
+			return err
+		}
+		rawValue, err := json.Marshal(price)
+		if err != nil {
+			return err
+		}
+		if err := rawBucket.Put(timeKey(ts), rawValue); err != nil {
+			return err
+		}
+
+		for _, tf := range timeframeDurations {
+			bucket, err := tx.CreateBucketIfNotExists(ohlcBucketName(symbol, tf.name))
+			if err != nil {
+				return err
+			}
+			periodStart := ts.Truncate(tf.duration)
+			key := timeKey(periodStart)
+
+			var candle Candle
+			if existing := bucket.Get(key); existing != nil {
+				if err := json.Unmarshal(existing, &candle); err != nil {
+					return err
+				}
+				candle.High = math.Max(candle.High, price)
+				candle.Low = math.Min(candle.Low, price)
+				candle.Close = price
+			} else {
+				candle = Candle{Timestamp: periodStart, Open: price, High: price, Low: price, Close: price}
+			}
+
+			encoded, err := json.Marshal(candle)
+			if err != nil {
+				return err
+			}
+			if err := bucket.Put(key, encoded); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Candles returns up to limit of the most recent candles for symbol at the
+// given timeframe, oldest first.
+func (s *PriceStore) Candles(symbol string, tf Timeframe, limit int) ([]Candle, error) {
+	var candles []Candle
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(ohlcBucketName(symbol, tf))
+		if bucket == nil {
+			return nil
+		}
+		c := bucket.Cursor()
+		for k, v := c.Last(); k != nil && len(candles) < limit; k, v = c.Prev() {
+			var candle Candle
+			if err := json.Unmarshal(v, &candle); err != nil {
+				return err
+			}
+			candles = append(candles, candle)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(candles)-1; i < j; i, j = i+1, j-1 {
+		candles[i], candles[j] = candles[j], candles[i]
+	}
+	return candles, nil
+}
+
+// ParseTimeframe validates a user-supplied timeframe string like "1h".
+func ParseTimeframe(s string) (Timeframe, error) {
+	for _, tf := range timeframeDurations {
+		if string(tf.name) == s {
+			return tf.name, nil
+		}
+	}
+	return "", fmt.Errorf("unknown timeframe %q", s)
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPriceStoreRollsUpOHLC(t *testing.T) {
+	store, err := NewPriceStore(filepath.Join(t.TempDir(), "prices.db"))
+	if err != nil {
+		t.Fatalf("NewPriceStore: %v", err)
+	}
+	defer store.Close()
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	ticks := []struct {
+		at    time.Time
+		price float64
+	}{
+		{base, 10},
+		{base.Add(20 * time.Second), 12},
+		{base.Add(40 * time.Second), 8},
+		{base.Add(70 * time.Second), 11}, // rolls into the next 1m candle
+	}
+	for _, tick := range ticks {
+		if err := store.WritePrice("SOL", tick.at, tick.price); err != nil {
+			t.Fatalf("WritePrice(%v): %v", tick.at, err)
+		}
+	}
+
+	candles, err := store.Candles("SOL", Timeframe1m, 10)
+	if err != nil {
+		t.Fatalf("Candles: %v", err)
+	}
+	if len(candles) != 2 {
+		t.Fatalf("got %d candles, want 2: %+v", len(candles), candles)
+	}
+
+	first := candles[0]
+	if first.Open != 10 || first.High != 12 || first.Low != 8 || first.Close != 8 {
+		t.Fatalf("first candle OHLC = %+v, want Open=10 High=12 Low=8 Close=8", first)
+	}
+
+	second := candles[1]
+	if second.Open != 11 || second.High != 11 || second.Low != 11 || second.Close != 11 {
+		t.Fatalf("second candle OHLC = %+v, want Open=High=Low=Close=11", second)
+	}
+}
+
+func TestPriceStoreCandlesLimit(t *testing.T) {
+	store, err := NewPriceStore(filepath.Join(t.TempDir(), "prices.db"))
+	if err != nil {
+		t.Fatalf("NewPriceStore: %v", err)
+	}
+	defer store.Close()
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		at := base.Add(time.Duration(i) * time.Hour)
+		if err := store.WritePrice("ETH", at, float64(i)); err != nil {
+			t.Fatalf("WritePrice: %v", err)
+		}
+	}
+
+	candles, err := store.Candles("ETH", Timeframe1h, 2)
+	if err != nil {
+		t.Fatalf("Candles: %v", err)
+	}
+	if len(candles) != 2 {
+		t.Fatalf("got %d candles, want 2", len(candles))
+	}
+	// Most recent 2 candles, oldest first.
+	if candles[0].Close != 3 || candles[1].Close != 4 {
+		t.Fatalf("candles = %+v, want closes [3 4]", candles)
+	}
+}
+
+func TestParseTimeframe(t *testing.T) {
+	tf, err := ParseTimeframe("1h")
+	if err != nil || tf != Timeframe1h {
+		t.Fatalf("ParseTimeframe(1h) = %v, %v; want Timeframe1h, nil", tf, err)
+	}
+	if _, err := ParseTimeframe("nope"); err == nil {
+		t.Fatal("expected an error for an unknown timeframe")
+	}
+}
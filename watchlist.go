@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultWatchlistPath is where per-chat watchlists are persisted between
+// restarts. It's a flat JSON file, in keeping with the rest of the bot's
+// on-disk state.
+const defaultWatchlistPath = ".watchlists.json"
+
+// WatchlistStore tracks which symbols each chat wants to hear about, so the
+// poller can union them into a single upstream query and fan each chart back
+// out only to the chats that asked for it.
+type WatchlistStore struct {
+	mu   sync.Mutex
+	path string
+	data map[int64]map[string]bool
+}
+
+// NewWatchlistStore loads (or initializes) a watchlist store at path.
+func NewWatchlistStore(path string) (*WatchlistStore, error) {
+	s := &WatchlistStore{
+		path: path,
+		data: make(map[int64]map[string]bool),
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *WatchlistStore) load() error {
+	raw, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var onDisk map[string][]string
+	if err := json.Unmarshal(raw, &onDisk); err != nil {
+		return err
+	}
+	for chatIDStr, symbols := range onDisk {
+		chatID, err := strconv.ParseInt(chatIDStr, 10, 64)
+		if err != nil {
+			continue
+		}
+		set := make(map[string]bool, len(symbols))
+		for _, sym := range symbols {
+			set[strings.ToUpper(sym)] = true
+		}
+		s.data[chatID] = set
+	}
+	return nil
+}
+
+// save must be called with s.mu held.
+func (s *WatchlistStore) save() error {
+	onDisk := make(map[string][]string, len(s.data))
+	for chatID, set := range s.data {
+		symbols := make([]string, 0, len(set))
+		for sym := range set {
+			symbols = append(symbols, sym)
+		}
+		sort.Strings(symbols)
+		onDisk[strconv.FormatInt(chatID, 10)] = symbols
+	}
+
+	raw, err := json.MarshalIndent(onDisk, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, raw, 0600)
+}
+
+// Add subscribes chatID to symbol, persisting the change.
+func (s *WatchlistStore) Add(chatID int64, symbol string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	symbol = strings.ToUpper(symbol)
+	if s.data[chatID] == nil {
+		s.data[chatID] = make(map[string]bool)
+	}
+	s.data[chatID][symbol] = true
+	return s.save()
+}
+
+// Remove unsubscribes chatID from symbol, persisting the change.
+func (s *WatchlistStore) Remove(chatID int64, symbol string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	symbol = strings.ToUpper(symbol)
+	delete(s.data[chatID], symbol)
+	return s.save()
+}
+
+// List returns the symbols chatID is subscribed to, sorted.
+func (s *WatchlistStore) List(chatID int64) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	symbols := make([]string, 0, len(s.data[chatID]))
+	for sym := range s.data[chatID] {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// Symbols returns the union of every chat's watchlist, sorted, for the
+// poller to fetch in a single batch.
+func (s *WatchlistStore) Symbols() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[string]bool)
+	for _, set := range s.data {
+		for sym := range set {
+			seen[sym] = true
+		}
+	}
+	symbols := make([]string, 0, len(seen))
+	for sym := range seen {
+		symbols = append(symbols, sym)
+	}
+	sort.Strings(symbols)
+	return symbols
+}
+
+// ChatsFor returns the chats subscribed to symbol, so the poller knows who
+// to send a given chart to.
+func (s *WatchlistStore) ChatsFor(symbol string) []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	symbol = strings.ToUpper(symbol)
+	chats := make([]int64, 0)
+	for chatID, set := range s.data {
+		if set[symbol] {
+			chats = append(chats, chatID)
+		}
+	}
+	sort.Slice(chats, func(i, j int) bool { return chats[i] < chats[j] })
+	return chats
+}